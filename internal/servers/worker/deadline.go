@@ -0,0 +1,239 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/boundary/internal/session"
+)
+
+// deadlineTimer is a single armable deadline, modeled on netstack's
+// deadlineTimer: a *time.Timer guarded by a mutex, paired with a channel
+// that is closed when the deadline fires or the timer is stopped and
+// recreated every time the timer is re-armed, so a caller blocked in a select on an old
+// channel never race the timer being reset out from under it. The same
+// type backs read, write, and absolute deadlines on a connection.
+//
+// time.Timer.Stop cannot un-fire a timer whose AfterFunc goroutine has
+// already started running, so setDeadline/stop alone aren't enough to
+// keep a stale fire from acting on a deadline that has since been
+// extended or disarmed: epoch is bumped on every arm/stop and captured by
+// the AfterFunc closure, and the closure checks it still matches under
+// d.mu before doing anything, making a stale fire a no-op.
+type deadlineTimer struct {
+	mu sync.Mutex
+
+	timer    *time.Timer
+	timerSet bool
+	cancel   chan struct{}
+	epoch    uint64
+}
+
+// newDeadlineTimer returns a deadlineTimer ready for use.
+func newDeadlineTimer() *deadlineTimer {
+	return &deadlineTimer{
+		cancel: make(chan struct{}),
+	}
+}
+
+// done returns the channel for the deadline as currently armed. It is
+// closed when the deadline expires or the timer is stopped. Callers that
+// need to keep waiting across a re-arm should call done again rather than
+// caching the channel.
+func (d *deadlineTimer) done() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cancel
+}
+
+// setDeadline arms the timer to invoke onExpire at t. A zero t disarms the
+// timer without invoking onExpire. Any previously armed timer is stopped
+// first, and its epoch is bumped so a fire already in flight from that
+// prior arming is discarded instead of acting on the new deadline.
+func (d *deadlineTimer) setDeadline(t time.Time, onExpire func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.stopLocked()
+	d.cancel = make(chan struct{})
+
+	if t.IsZero() {
+		return
+	}
+
+	epoch := d.epoch
+	d.timer = time.AfterFunc(time.Until(t), func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		if epoch != d.epoch {
+			// Stale fire: setDeadline or stop ran before this callback
+			// could acquire d.mu (time.Timer.Stop cannot un-fire a
+			// goroutine that has already started running). The deadline
+			// this was armed for no longer applies, so do nothing —
+			// neither invoking onExpire nor touching cancel, which may
+			// now belong to a subsequent re-arm.
+			return
+		}
+
+		onExpire()
+		d.closeCancelLocked()
+	})
+	d.timerSet = true
+}
+
+// stop disarms the timer without invoking onExpire, bumps the epoch so
+// any fire already in flight is discarded, and closes out the current
+// cancel channel.
+func (d *deadlineTimer) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopLocked()
+}
+
+func (d *deadlineTimer) stopLocked() {
+	d.epoch++
+	if d.timerSet {
+		d.timer.Stop()
+		d.timerSet = false
+	}
+	d.closeCancelLocked()
+}
+
+// closeCancelLocked closes the current cancel channel, if not already
+// closed. It stays closed until setDeadline replaces it.
+func (d *deadlineTimer) closeCancelLocked() {
+	select {
+	case <-d.cancel:
+	default:
+		close(d.cancel)
+	}
+}
+
+// deadlineExceededEvent identifies the connection whose deadline fired.
+type deadlineExceededEvent struct {
+	sessionID    string
+	connectionID string
+}
+
+// deadlineCh returns the channel deadline timers send on when they fire,
+// creating it on first use. Using sync.Once here, rather than the
+// lazy nil-check startDeadlineReaper used to do, means the channel exists
+// as soon as any deadline is armed even if startDeadlineReaper is never
+// called, so an onExpire callback is guaranteed a non-nil channel to send
+// on instead of blocking forever on a nil channel.
+func (w *Worker) deadlineCh() chan deadlineExceededEvent {
+	w.deadlineChOnce.Do(func() {
+		w.deadlineExceededCh = make(chan deadlineExceededEvent)
+	})
+	return w.deadlineExceededCh
+}
+
+// startDeadlineReaper launches the background goroutine that watches for
+// expired connection deadlines and closes the affected connections on the
+// controller, until ctx is done. It is safe to call once per Worker.
+func (w *Worker) startDeadlineReaper(ctx context.Context) {
+	ch := w.deadlineCh()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev := <-ch:
+				_, err := w.closeConnections(ctx, []ConnectionCloseRequest{
+					{
+						ConnectionId: ev.connectionID,
+						SessionId:    ev.sessionID,
+						Reason:       session.DeadlineExceededReason,
+					},
+				})
+				if err != nil && w.logger != nil {
+					w.logger.Error("error closing connection after deadline exceeded",
+						"session_id", ev.sessionID,
+						"connection_id", ev.connectionID,
+						"err", err,
+					)
+				}
+			}
+		}
+	}()
+}
+
+// lookupConnInfo returns the connInfo tracked for connectionId under
+// sessionId.
+func (w *Worker) lookupConnInfo(sessionId, connectionId string) (*connInfo, error) {
+	siRaw, ok := w.sessionInfoMap.Load(sessionId)
+	if !ok {
+		return nil, fmt.Errorf("could not find session ID %q in local state", sessionId)
+	}
+	si := siRaw.(*sessionInfo)
+
+	si.RLock()
+	ci, ok := si.connInfoMap[connectionId]
+	si.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("could not find connection ID %q for session ID %q in local state", connectionId, sessionId)
+	}
+	return ci, nil
+}
+
+// armDeadline sets timer to fire at deadline, enqueueing the connection
+// onto the deadline reaper's channel when it fires. The send is guarded by
+// ctx: if ctx is done before the reaper receives the event, the event is
+// dropped instead of leaking the goroutine time.AfterFunc spawns. With a
+// ctx that is never done, the send waits until the reaper receives it.
+func (w *Worker) armDeadline(ctx context.Context, timer *deadlineTimer, sessionId, connectionId string, deadline time.Time) {
+	ch := w.deadlineCh()
+	timer.setDeadline(deadline, func() {
+		select {
+		case ch <- deadlineExceededEvent{sessionID: sessionId, connectionID: connectionId}:
+		case <-ctx.Done():
+		}
+	})
+}
+
+// SetConnectionDeadline arms the absolute deadline for the given
+// connection: the point at which the connection is torn down regardless
+// of activity. Once deadline is reached, the connection is enqueued for
+// close on the controller with session.DeadlineExceededReason. A zero
+// deadline disarms any previously set deadline. ctx bounds how long the
+// eventual close enqueue may block waiting on the deadline reaper.
+func (w *Worker) SetConnectionDeadline(ctx context.Context, sessionId, connectionId string, deadline time.Time) error {
+	ci, err := w.lookupConnInfo(sessionId, connectionId)
+	if err != nil {
+		return err
+	}
+	w.armDeadline(ctx, ci.absoluteDeadline, sessionId, connectionId, deadline)
+	return nil
+}
+
+// SetConnectionReadDeadline arms the read deadline for the given
+// connection: the point by which the next read must have made progress.
+// Once deadline is reached, the connection is enqueued for close on the
+// controller with session.DeadlineExceededReason. A zero deadline disarms
+// any previously set read deadline.
+func (w *Worker) SetConnectionReadDeadline(ctx context.Context, sessionId, connectionId string, deadline time.Time) error {
+	ci, err := w.lookupConnInfo(sessionId, connectionId)
+	if err != nil {
+		return err
+	}
+	w.armDeadline(ctx, ci.readDeadline, sessionId, connectionId, deadline)
+	return nil
+}
+
+// SetConnectionWriteDeadline arms the write deadline for the given
+// connection: the point by which the in-flight write must complete. Once
+// deadline is reached, the connection is enqueued for close on the
+// controller with session.DeadlineExceededReason. A zero deadline disarms
+// any previously set write deadline.
+func (w *Worker) SetConnectionWriteDeadline(ctx context.Context, sessionId, connectionId string, deadline time.Time) error {
+	ci, err := w.lookupConnInfo(sessionId, connectionId)
+	if err != nil {
+		return err
+	}
+	w.armDeadline(ctx, ci.writeDeadline, sessionId, connectionId, deadline)
+	return nil
+}
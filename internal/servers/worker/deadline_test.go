@@ -0,0 +1,156 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadlineTimerFires(t *testing.T) {
+	d := newDeadlineTimer()
+	fired := make(chan struct{})
+	d.setDeadline(time.Now().Add(10*time.Millisecond), func() {
+		close(fired)
+	})
+
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("deadline did not fire in time")
+	}
+
+	select {
+	case <-d.done():
+	case <-time.After(time.Second):
+		t.Fatal("cancel channel was not closed after deadline fired")
+	}
+}
+
+func TestDeadlineTimerStopPreventsFire(t *testing.T) {
+	require := require.New(t)
+
+	d := newDeadlineTimer()
+	fired := make(chan struct{})
+	d.setDeadline(time.Now().Add(50*time.Millisecond), func() {
+		close(fired)
+	})
+	d.stop()
+
+	select {
+	case <-fired:
+		t.Fatal("onExpire ran after stop")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	select {
+	case <-d.done():
+	default:
+		t.Fatal("cancel channel should be closed after stop")
+	}
+	require.NotNil(d)
+}
+
+func TestDeadlineTimerZeroDisarms(t *testing.T) {
+	d := newDeadlineTimer()
+	d.setDeadline(time.Now().Add(10*time.Millisecond), func() {
+		t.Fatal("onExpire should not run")
+	})
+	d.setDeadline(time.Time{}, func() {
+		t.Fatal("onExpire should not run")
+	})
+
+	select {
+	case <-d.done():
+		t.Fatal("cancel channel should not be closed when disarmed with a zero deadline")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDeadlineTimerReArmDiscardsStaleFire(t *testing.T) {
+	require := require.New(t)
+
+	d := newDeadlineTimer()
+	var stale, final int32
+
+	// Arm with a near-immediate deadline and re-arm right away, over and
+	// over, so the AfterFunc goroutine behind an earlier arming has every
+	// chance to start running concurrently with the next setDeadline. If
+	// the epoch guard didn't discard it, onExpire from one of the earlier
+	// armings would fire as "stale" against the final, longer deadline.
+	for i := 0; i < 200; i++ {
+		d.setDeadline(time.Now().Add(time.Microsecond), func() {
+			atomic.AddInt32(&stale, 1)
+		})
+	}
+	d.setDeadline(time.Now().Add(20*time.Millisecond), func() {
+		atomic.AddInt32(&final, 1)
+	})
+
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(int32(0), atomic.LoadInt32(&stale))
+	require.Equal(int32(1), atomic.LoadInt32(&final))
+}
+
+func newTestConnWorker(sessionId, connectionId string) *Worker {
+	m := new(sync.Map)
+	m.Store(sessionId, &sessionInfo{
+		id: sessionId,
+		connInfoMap: map[string]*connInfo{
+			connectionId: newConnInfo(connectionId),
+		},
+	})
+	return &Worker{sessionInfoMap: m}
+}
+
+func TestSetConnectionReadAndWriteDeadlineEnqueueOnFire(t *testing.T) {
+	require := require.New(t)
+
+	w := newTestConnWorker("one", "foo")
+	ctx := context.Background()
+
+	require.NoError(w.SetConnectionReadDeadline(ctx, "one", "foo", time.Now().Add(10*time.Millisecond)))
+
+	select {
+	case ev := <-w.deadlineCh():
+		require.Equal(deadlineExceededEvent{sessionID: "one", connectionID: "foo"}, ev)
+	case <-time.After(time.Second):
+		t.Fatal("read deadline did not enqueue an event in time")
+	}
+
+	require.NoError(w.SetConnectionWriteDeadline(ctx, "one", "foo", time.Now().Add(10*time.Millisecond)))
+
+	select {
+	case ev := <-w.deadlineCh():
+		require.Equal(deadlineExceededEvent{sessionID: "one", connectionID: "foo"}, ev)
+	case <-time.After(time.Second):
+		t.Fatal("write deadline did not enqueue an event in time")
+	}
+}
+
+func TestSetConnectionDeadlineUnknownConnection(t *testing.T) {
+	require := require.New(t)
+
+	w := newTestConnWorker("one", "foo")
+	require.Error(w.SetConnectionDeadline(context.Background(), "one", "bar", time.Now().Add(time.Millisecond)))
+	require.Error(w.SetConnectionDeadline(context.Background(), "two", "foo", time.Now().Add(time.Millisecond)))
+}
+
+func TestArmDeadlineDoesNotLeakWhenContextCanceled(t *testing.T) {
+	w := newTestConnWorker("one", "foo")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.NoError(t, w.SetConnectionDeadline(ctx, "one", "foo", time.Now().Add(10*time.Millisecond)))
+
+	// Nothing drains w.deadlineCh(); with ctx already canceled, onExpire's
+	// guarded send must take the ctx.Done() branch instead of blocking
+	// forever. If it blocked, this goroutine would leak past the test but
+	// would not fail it outright, so the real assertion is that the test
+	// (and any -race run) completes without hanging.
+	time.Sleep(50 * time.Millisecond)
+}
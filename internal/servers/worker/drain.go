@@ -0,0 +1,195 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/boundary/internal/session"
+)
+
+// drainPollInterval is how often a draining session is checked for
+// connections that have closed on their own during the grace period.
+const drainPollInterval = 100 * time.Millisecond
+
+// DrainWarner is notified before a draining session's connections are
+// force-closed, so it can push an in-band control message (e.g. over the
+// session's mux) telling the client to wrap up.
+type DrainWarner interface {
+	WarnDraining(sessionId string) error
+}
+
+// DrainOptions configures Worker.Drain.
+type DrainOptions struct {
+	// MaxConcurrentCloses bounds how many sessions are drained at once.
+	// Defaults to 1 if zero or negative.
+	MaxConcurrentCloses int
+	// GracePeriod is how long a draining session is given to close its
+	// own connections, after being warned, before the worker force-closes
+	// them with session.AdminTerminatedReason.
+	GracePeriod time.Duration
+}
+
+// DrainReport summarizes the outcome of a Drain call.
+type DrainReport struct {
+	TotalSessions    int
+	TotalConnections int
+	ClosedGracefully int
+	ClosedByDeadline int
+	Failed           int
+}
+
+// Drain marks the worker as draining (see isDraining), then closes every
+// connection it currently knows about in waves bounded by
+// opts.MaxConcurrentCloses. Each session is given opts.GracePeriod to
+// close its own connections, after being warned via drainWarner (if set),
+// before the worker force-closes whatever remains. The draining mark is
+// not checked by any session-accept path in this package, so Drain does
+// not itself refuse new sessions. It is meant to be
+// wired into SIGTERM handling so a kubectl drain-style rollout doesn't
+// sever active user sessions.
+func (w *Worker) Drain(ctx context.Context, opts DrainOptions) (*DrainReport, error) {
+	atomic.StoreInt32(&w.draining, 1)
+
+	maxConcurrent := opts.MaxConcurrentCloses
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+
+	type sessionWork struct {
+		id    string
+		si    *sessionInfo
+		conns []string
+	}
+
+	var work []sessionWork
+	w.sessionInfoMap.Range(func(key, value interface{}) bool {
+		si := value.(*sessionInfo)
+
+		si.Lock()
+		if si.state == sessionStateDrained {
+			si.Unlock()
+			return true
+		}
+		si.state = sessionStateDraining
+		conns := make([]string, 0, len(si.connInfoMap))
+		for id := range si.connInfoMap {
+			conns = append(conns, id)
+		}
+		si.Unlock()
+
+		work = append(work, sessionWork{id: key.(string), si: si, conns: conns})
+		return true
+	})
+
+	report := &DrainReport{TotalSessions: len(work)}
+	for _, sw := range work {
+		report.TotalConnections += len(sw.conns)
+	}
+
+	sem := make(chan struct{}, maxConcurrent)
+	var wg sync.WaitGroup
+	var closedGracefully, closedByDeadline, failed int64
+
+	for _, sw := range work {
+		sw := sw
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			graceful, forced, fail := w.drainSession(ctx, sw.id, sw.conns, opts.GracePeriod)
+
+			sw.si.Lock()
+			sw.si.state = sessionStateDrained
+			sw.si.Unlock()
+
+			atomic.AddInt64(&closedGracefully, int64(graceful))
+			atomic.AddInt64(&closedByDeadline, int64(forced))
+			atomic.AddInt64(&failed, int64(fail))
+		}()
+	}
+	wg.Wait()
+
+	report.ClosedGracefully = int(closedGracefully)
+	report.ClosedByDeadline = int(closedByDeadline)
+	report.Failed = int(failed)
+	return report, nil
+}
+
+// drainSession warns the session's client that it is draining, waits up
+// to grace for its connections to close on their own, then force-closes
+// whatever remains. It returns how many connections closed gracefully,
+// how many were force-closed, and how many could not be closed at all.
+func (w *Worker) drainSession(ctx context.Context, sessionId string, connIds []string, grace time.Duration) (graceful, forced, failed int) {
+	if len(connIds) == 0 {
+		return 0, 0, 0
+	}
+
+	if w.drainWarner != nil {
+		if err := w.drainWarner.WarnDraining(sessionId); err != nil && w.logger != nil {
+			w.logger.Error("error warning client of drain", "session_id", sessionId, "err", err)
+		}
+	}
+
+	remaining := w.waitForGracefulClose(sessionId, connIds, grace)
+	graceful = len(connIds) - len(remaining)
+	if len(remaining) == 0 {
+		return graceful, 0, 0
+	}
+
+	closeRequests := make([]ConnectionCloseRequest, 0, len(remaining))
+	for _, id := range remaining {
+		closeRequests = append(closeRequests, ConnectionCloseRequest{
+			ConnectionId: id,
+			SessionId:    sessionId,
+			Reason:       session.AdminTerminatedReason,
+		})
+	}
+
+	results, err := w.closeConnections(ctx, closeRequests)
+	if err != nil && w.logger != nil {
+		w.logger.Error("error force-closing connections during drain", "session_id", sessionId, "err", err)
+	}
+	for _, r := range results {
+		if r.Result == CloseResultClosed {
+			forced++
+			continue
+		}
+		failed++
+	}
+	return graceful, forced, failed
+}
+
+// waitForGracefulClose polls the session's local connection state until
+// every connection in connIds has a close time set, or grace elapses,
+// whichever comes first. It returns the connection IDs still open.
+func (w *Worker) waitForGracefulClose(sessionId string, connIds []string, grace time.Duration) []string {
+	deadline := time.Now().Add(grace)
+	remaining := append([]string(nil), connIds...)
+
+	for {
+		siRaw, ok := w.sessionInfoMap.Load(sessionId)
+		if !ok {
+			return nil
+		}
+		si := siRaw.(*sessionInfo)
+
+		si.RLock()
+		stillOpen := remaining[:0]
+		for _, id := range remaining {
+			if ci, ok := si.connInfoMap[id]; ok && ci.closeTime.IsZero() {
+				stillOpen = append(stillOpen, id)
+			}
+		}
+		si.RUnlock()
+		remaining = stillOpen
+
+		if len(remaining) == 0 || grace <= 0 || time.Now().After(deadline) {
+			return remaining
+		}
+		time.Sleep(drainPollInterval)
+	}
+}
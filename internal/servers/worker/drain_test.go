@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForGracefulCloseReturnsEarlyWhenAllClosed(t *testing.T) {
+	require := require.New(t)
+
+	m := new(sync.Map)
+	m.Store("one", &sessionInfo{
+		id: "one",
+		connInfoMap: map[string]*connInfo{
+			"foo": &connInfo{id: "foo", closeTime: time.Now()},
+		},
+	})
+	w := &Worker{sessionInfoMap: m}
+
+	start := time.Now()
+	remaining := w.waitForGracefulClose("one", []string{"foo"}, time.Minute)
+	require.Empty(remaining)
+	require.Less(time.Since(start), time.Second)
+}
+
+func TestWaitForGracefulCloseTimesOutOnStillOpenConnections(t *testing.T) {
+	require := require.New(t)
+
+	m := new(sync.Map)
+	m.Store("one", &sessionInfo{
+		id: "one",
+		connInfoMap: map[string]*connInfo{
+			"foo": &connInfo{id: "foo"},
+		},
+	})
+	w := &Worker{sessionInfoMap: m}
+
+	remaining := w.waitForGracefulClose("one", []string{"foo"}, 200*time.Millisecond)
+	require.Equal([]string{"foo"}, remaining)
+}
+
+func TestDrainReportsGracefulWhenConnectionsAlreadyClosed(t *testing.T) {
+	require := require.New(t)
+
+	m := new(sync.Map)
+	si := &sessionInfo{
+		id: "one",
+		connInfoMap: map[string]*connInfo{
+			"foo": &connInfo{id: "foo", closeTime: time.Now()},
+			"bar": &connInfo{id: "bar", closeTime: time.Now()},
+		},
+	}
+	m.Store("one", si)
+	w := &Worker{sessionInfoMap: m}
+
+	report, err := w.Drain(context.Background(), DrainOptions{MaxConcurrentCloses: 2})
+	require.NoError(err)
+	require.Equal(1, report.TotalSessions)
+	require.Equal(2, report.TotalConnections)
+	require.Equal(2, report.ClosedGracefully)
+	require.Equal(0, report.ClosedByDeadline)
+	require.Equal(0, report.Failed)
+	require.True(w.isDraining())
+
+	si.RLock()
+	defer si.RUnlock()
+	require.Equal(sessionStateDrained, si.state)
+}
@@ -0,0 +1,133 @@
+package worker
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics bundles the Prometheus collectors for the worker's close path.
+// It is held on the Worker rather than registered against the global
+// default registry so tests can inject their own prometheus.Registerer
+// and assert counter deltas directly.
+type metrics struct {
+	connectionsClosed  *prometheus.CounterVec
+	closeRequestLength prometheus.Histogram
+	closeErrors        *prometheus.CounterVec
+	activeConnections  *prometheus.GaugeVec
+}
+
+// newMetrics registers the worker's close-path collectors against reg and
+// returns the bundle used to record them.
+func newMetrics(reg prometheus.Registerer) *metrics {
+	return &metrics{
+		connectionsClosed: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "boundary_worker_connections_closed_total",
+			Help: "Total number of connections the worker has asked the controller to close, by reason and outcome status.",
+		}, []string{"reason", "status"}),
+		closeRequestLength: promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
+			Name: "boundary_worker_close_request_duration_seconds",
+			Help: "Duration of CloseConnection RPCs issued by the worker to the controller.",
+		}),
+		closeErrors: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "boundary_worker_close_errors_total",
+			Help: "Total number of errors encountered while closing connections, by kind.",
+		}, []string{"kind"}),
+		activeConnections: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "boundary_worker_active_connections",
+			Help: "Number of connections the worker currently believes are active, by session ID.",
+		}, []string{"session_id"}),
+	}
+}
+
+// EnableMetrics registers the worker's close-path collectors against reg
+// and mounts the scrape endpoint at /metrics on mux, which should be the
+// mux backing the worker's existing listener. It is a no-op if called
+// more than once.
+//
+// Discovering that /metrics endpoint is left to the operator's Prometheus
+// setup rather than anything this package wires up. For example, a
+// Prometheus server can find every worker registered in Consul with a
+// scrape_configs entry along these lines, adapting the Consul address and
+// service tag to the deployment:
+//
+//	scrape_configs:
+//	  - job_name: boundary-worker
+//	    consul_sd_configs:
+//	      - server: 127.0.0.1:8500
+//	        services: ["boundary-worker"]
+//	    relabel_configs:
+//	      - source_labels: [__meta_consul_tags]
+//	        regex: .*,metrics,.*
+//	        action: keep
+func (w *Worker) EnableMetrics(reg *prometheus.Registry, mux *http.ServeMux) {
+	if w.metrics != nil {
+		return
+	}
+	w.metrics = newMetrics(reg)
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+}
+
+const (
+	closeErrorKindMissingSession        = "missing_session"
+	closeErrorKindMissingConnection     = "missing_connection"
+	closeErrorKindControllerUnreachable = "controller_unreachable"
+)
+
+// recordCloseResult increments the connections_closed_total counter for a
+// single connection's close outcome.
+func (m *metrics) recordCloseResult(reason, status string) {
+	if m == nil {
+		return
+	}
+	m.connectionsClosed.WithLabelValues(reason, status).Inc()
+}
+
+// recordCloseError increments the close_errors_total counter for kind.
+func (m *metrics) recordCloseError(kind string) {
+	if m == nil {
+		return
+	}
+	m.closeErrors.WithLabelValues(kind).Inc()
+}
+
+// recordCloseRequestDuration observes how long a CloseConnection RPC took.
+func (m *metrics) recordCloseRequestDuration(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.closeRequestLength.Observe(d.Seconds())
+}
+
+// refreshActiveConnections recomputes the active_connections gauge by
+// ranging over sessionInfoMap and counting, per session, the connections
+// that have not yet been closed. It replaces the full gauge state on
+// every call so sessions that have gone away are not left with a stale
+// value.
+func (w *Worker) refreshActiveConnections() {
+	if w.metrics == nil {
+		return
+	}
+
+	w.metrics.activeConnections.Reset()
+	w.sessionInfoMap.Range(func(key, value interface{}) bool {
+		si := value.(*sessionInfo)
+
+		si.RLock()
+		var active int
+		for _, ci := range si.connInfoMap {
+			if ci.closeTime.IsZero() {
+				active++
+			}
+		}
+		si.RUnlock()
+
+		if active > 0 {
+			w.metrics.activeConnections.WithLabelValues(key.(string)).Set(float64(active))
+		}
+		return true
+	})
+}
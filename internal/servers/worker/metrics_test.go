@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	pbs "github.com/hashicorp/boundary/internal/gen/controller/servers/services"
+	"github.com/hashicorp/boundary/internal/session"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func counterValue(t *testing.T, c *prometheus.CounterVec, labels prometheus.Labels) float64 {
+	t.Helper()
+	m := &dto.Metric{}
+	require.NoError(t, c.With(labels).Write(m))
+	return m.GetCounter().GetValue()
+}
+
+func gaugeValue(t *testing.T, g *prometheus.GaugeVec, labels prometheus.Labels) float64 {
+	t.Helper()
+	m := &dto.Metric{}
+	require.NoError(t, g.With(labels).Write(m))
+	return m.GetGauge().GetValue()
+}
+
+func TestWorkerSetCloseTimeForResponseRecordsMetrics(t *testing.T) {
+	require := require.New(t)
+
+	reg := prometheus.NewRegistry()
+	w := &Worker{
+		sessionInfoMap: func() *sync.Map {
+			m := new(sync.Map)
+			m.Store("one", &sessionInfo{
+				id: "one",
+				connInfoMap: map[string]*connInfo{
+					"foo": &connInfo{id: "foo"},
+					"baz": &connInfo{id: "baz"},
+				},
+			})
+			return m
+		}(),
+	}
+	w.EnableMetrics(reg, http.NewServeMux())
+
+	closeRequests := []ConnectionCloseRequest{
+		{ConnectionId: "foo", SessionId: "one"},
+		{ConnectionId: "bar", SessionId: "two"},
+	}
+	response := &pbs.CloseConnectionResponse{
+		CloseResponseData: []*pbs.CloseConnectionResponseData{
+			{ConnectionId: "foo", Status: pbs.CONNECTIONSTATUS_CONNECTIONSTATUS_CLOSED},
+			{ConnectionId: "bar", Status: pbs.CONNECTIONSTATUS_CONNECTIONSTATUS_CLOSED},
+		},
+	}
+
+	w.setCloseTimeForResponse(closeRequests, response)
+
+	require.Equal(float64(1), counterValue(t, w.metrics.connectionsClosed, prometheus.Labels{
+		"reason": session.UnknownReason.String(), "status": string(CloseResultClosed),
+	}))
+	require.Equal(float64(1), counterValue(t, w.metrics.closeErrors, prometheus.Labels{
+		"kind": closeErrorKindMissingSession,
+	}))
+
+	// "foo" closed, "baz" is still open, so session "one" should show
+	// exactly one active connection, derived from sessionInfoMap rather
+	// than incremented/decremented independently.
+	require.Equal(float64(1), gaugeValue(t, w.metrics.activeConnections, prometheus.Labels{
+		"session_id": "one",
+	}))
+}
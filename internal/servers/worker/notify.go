@@ -0,0 +1,181 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/boundary/internal/session"
+)
+
+// CloseEvent describes a single connection close, delivered to every
+// registered CloseNotifier so external systems (billing, audit, SIEM) can
+// maintain a push-based feed of session lifecycle events without polling
+// the controller.
+type CloseEvent struct {
+	SessionId    string
+	ConnectionId string
+	Reason       session.ClosedReason
+	BytesUp      int64
+	BytesDown    int64
+	Duration     time.Duration
+}
+
+// CloseNotifier delivers CloseEvents to some external transport (HTTP
+// webhook, NATS, syslog, Kafka, ...). Notify should only return an error
+// once it has exhausted any retries of its own; the worker does not retry
+// on its behalf.
+type CloseNotifier interface {
+	Notify(ctx context.Context, event CloseEvent) error
+}
+
+// dispatchCloseNotifications builds a CloseEvent for every connection
+// result successfully closed in this round and fans it out to the
+// worker's registered CloseNotifiers. Delivery happens in the background
+// so it never delays the caller's close response.
+func (w *Worker) dispatchCloseNotifications(ctx context.Context, closeRequests []ConnectionCloseRequest, results []ConnectionCloseResult) {
+	if len(w.closeNotifiers) == 0 {
+		return
+	}
+
+	reasons := make(map[string]session.ClosedReason, len(closeRequests))
+	for _, r := range closeRequests {
+		reasons[r.ConnectionId] = r.reason()
+	}
+
+	for _, res := range results {
+		if res.Result != CloseResultClosed {
+			continue
+		}
+
+		siRaw, ok := w.sessionInfoMap.Load(res.SessionId)
+		if !ok {
+			continue
+		}
+		si := siRaw.(*sessionInfo)
+		si.RLock()
+		ci, ok := si.connInfoMap[res.ConnectionId]
+		var bytesUp, bytesDown int64
+		var startTime, closeTime time.Time
+		if ok {
+			// Snapshot the fields we need while still holding si's lock,
+			// which is what guards connInfo.
+			bytesUp, bytesDown = ci.bytesUp, ci.bytesDown
+			startTime, closeTime = ci.startTime, ci.closeTime
+		}
+		si.RUnlock()
+		if !ok {
+			continue
+		}
+
+		event := CloseEvent{
+			SessionId:    res.SessionId,
+			ConnectionId: res.ConnectionId,
+			Reason:       reasons[res.ConnectionId],
+			BytesUp:      bytesUp,
+			BytesDown:    bytesDown,
+			Duration:     closeTime.Sub(startTime),
+		}
+		go w.notifyClose(ctx, event)
+	}
+}
+
+// notifyClose delivers event to every registered CloseNotifier, logging
+// (but not otherwise acting on) delivery failures.
+func (w *Worker) notifyClose(ctx context.Context, event CloseEvent) {
+	for _, n := range w.closeNotifiers {
+		if err := n.Notify(ctx, event); err != nil && w.logger != nil {
+			w.logger.Error("error delivering close notification",
+				"session_id", event.SessionId,
+				"connection_id", event.ConnectionId,
+				"err", err,
+			)
+		}
+	}
+}
+
+// WebhookCloseNotifier POSTs a JSON-encoded CloseEvent to a configured URL
+// for every close. The body is signed with HMAC-SHA256 so the receiver
+// can authenticate the worker as the sender, and delivery is retried with
+// exponential backoff on transport errors or a non-2xx response.
+type WebhookCloseNotifier struct {
+	url         string
+	secret      []byte
+	client      *http.Client
+	maxRetries  int
+	baseBackoff time.Duration
+}
+
+// NewWebhookCloseNotifier returns a WebhookCloseNotifier that POSTs to
+// url, signing each request body with secret.
+func NewWebhookCloseNotifier(url string, secret []byte) *WebhookCloseNotifier {
+	return &WebhookCloseNotifier{
+		url:         url,
+		secret:      secret,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		maxRetries:  5,
+		baseBackoff: 500 * time.Millisecond,
+	}
+}
+
+// Notify implements CloseNotifier.
+func (n *WebhookCloseNotifier) Notify(ctx context.Context, event CloseEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error marshaling close event: %w", err)
+	}
+	sig := n.sign(body)
+
+	var lastErr error
+	for attempt := 0; attempt <= n.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(n.baseBackoff * time.Duration(uint(1)<<uint(attempt-1))):
+			}
+		}
+
+		if err := n.post(ctx, body, sig); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+
+	return fmt.Errorf("giving up after %d attempts: %w", n.maxRetries+1, lastErr)
+}
+
+func (n *WebhookCloseNotifier) post(ctx context.Context, body []byte, sig string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error building close notification request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Boundary-Signature", sig)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error delivering close notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("close notification endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sign returns the hex-encoded HMAC-SHA256 of body using the notifier's
+// secret.
+func (n *WebhookCloseNotifier) sign(body []byte) string {
+	mac := hmac.New(sha256.New, n.secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
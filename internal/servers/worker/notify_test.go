@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookCloseNotifierSignsBody(t *testing.T) {
+	require := require.New(t)
+
+	secret := []byte("shh")
+	var gotBody []byte
+	var gotSig string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = ioutil.ReadAll(r.Body)
+		gotSig = r.Header.Get("X-Boundary-Signature")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	n := NewWebhookCloseNotifier(srv.URL, secret)
+	err := n.Notify(context.Background(), CloseEvent{SessionId: "one", ConnectionId: "foo"})
+	require.NoError(err)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(gotBody)
+	require.Equal(hex.EncodeToString(mac.Sum(nil)), gotSig)
+}
+
+func TestWebhookCloseNotifierRetriesOnFailure(t *testing.T) {
+	require := require.New(t)
+
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	n := NewWebhookCloseNotifier(srv.URL, []byte("shh"))
+	n.baseBackoff = 0
+	err := n.Notify(context.Background(), CloseEvent{SessionId: "one", ConnectionId: "foo"})
+	require.NoError(err)
+	require.Equal(3, attempts)
+}
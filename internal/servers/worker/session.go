@@ -0,0 +1,283 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	pbs "github.com/hashicorp/boundary/internal/gen/controller/servers/services"
+	"github.com/hashicorp/boundary/internal/session"
+)
+
+// sessionState tracks where a session sits in the worker's drain
+// lifecycle.
+type sessionState int
+
+const (
+	sessionStateActive sessionState = iota
+	sessionStateDraining
+	sessionStateDrained
+)
+
+// sessionInfo defines the session info we track on the worker.
+type sessionInfo struct {
+	sync.RWMutex
+	id          string
+	state       sessionState
+	connInfoMap map[string]*connInfo
+}
+
+// connInfo defines the connection info we track on the worker.
+type connInfo struct {
+	id        string
+	startTime time.Time
+	closeTime time.Time
+
+	// bytesUp and bytesDown hold bytes proxied from client to target and
+	// target to client, respectively, for reporting on close. Nothing in
+	// this package writes them, so close events report them as zero.
+	bytesUp   int64
+	bytesDown int64
+
+	// readDeadline, writeDeadline, and absoluteDeadline bound how long the
+	// connection may go without activity, how long a single write may
+	// take, and how long the connection may live overall, respectively.
+	// When any of them fires, the worker's deadline reaper enqueues the
+	// connection for close with session.DeadlineExceededReason.
+	readDeadline     *deadlineTimer
+	writeDeadline    *deadlineTimer
+	absoluteDeadline *deadlineTimer
+}
+
+// newConnInfo returns a connInfo with its deadline timers ready for use.
+func newConnInfo(id string) *connInfo {
+	return &connInfo{
+		id:               id,
+		startTime:        time.Now(),
+		readDeadline:     newDeadlineTimer(),
+		writeDeadline:    newDeadlineTimer(),
+		absoluteDeadline: newDeadlineTimer(),
+	}
+}
+
+// ConnectionCloseRequest describes a single connection to be closed on the
+// controller, along with why it is being closed. Reason defaults to
+// session.UnknownReason when left unset.
+type ConnectionCloseRequest struct {
+	ConnectionId string
+	SessionId    string
+	Reason       session.ClosedReason
+	// Cause, when set, is the underlying error (timeout, canceled context,
+	// network failure, ...) that led to this close request. It is kept
+	// local to the worker: closeConnections logs it alongside a
+	// controller-side failure and uses it to decide the resulting
+	// ConnectionCloseResult.Retryable, and it is not sent to the
+	// controller.
+	Cause error
+}
+
+// reason returns r.Reason, or session.UnknownReason if it is unset.
+func (r ConnectionCloseRequest) reason() session.ClosedReason {
+	var unset session.ClosedReason
+	if r.Reason == unset {
+		return session.UnknownReason
+	}
+	return r.Reason
+}
+
+// isRetryableCause reports whether a ConnectionCloseRequest.Cause
+// indicates the close is still worth retrying. A caller-side cancellation
+// or deadline means the caller has already given up on its own terms, so
+// retrying would just repeat work nobody wants; any other cause,
+// including none at all, is assumed transient and retryable.
+func isRetryableCause(cause error) bool {
+	if cause == nil {
+		return true
+	}
+	return !errors.Is(cause, context.Canceled) && !errors.Is(cause, context.DeadlineExceeded)
+}
+
+// CloseResult is the outcome of attempting to close a single connection
+// against the controller.
+type CloseResult string
+
+const (
+	// CloseResultClosed indicates the controller confirmed the connection
+	// is now closed.
+	CloseResultClosed CloseResult = "closed"
+	// CloseResultStillConnected indicates the controller reports the
+	// connection as still connected.
+	CloseResultStillConnected CloseResult = "still_connected"
+	// CloseResultSessionMissing indicates the worker has no local state
+	// for the connection's session.
+	CloseResultSessionMissing CloseResult = "session_missing"
+	// CloseResultConnectionMissing indicates the worker has no local state
+	// for the connection itself.
+	CloseResultConnectionMissing CloseResult = "connection_missing"
+	// CloseResultControllerError indicates the close request to the
+	// controller failed outright.
+	CloseResultControllerError CloseResult = "controller_error"
+)
+
+// ConnectionCloseResult is the per-connection outcome of a close request,
+// returned so callers can drive retry/backoff logic instead of inspecting
+// a flat list of errors.
+type ConnectionCloseResult struct {
+	ConnectionId string
+	SessionId    string
+	Result       CloseResult
+	Err          error
+	// Retryable is only meaningful alongside CloseResultControllerError;
+	// it reflects isRetryableCause(the originating request's Cause) and
+	// tells the caller's retry/backoff loop whether retrying this
+	// connection is worth attempting.
+	Retryable bool
+}
+
+// closeConnections asks the controller to close the given connections and
+// reconciles the response against local state, returning a per-connection
+// result.
+func (w *Worker) closeConnections(ctx context.Context, closeRequests []ConnectionCloseRequest) ([]ConnectionCloseResult, error) {
+	if len(closeRequests) == 0 {
+		return nil, nil
+	}
+
+	start := time.Now()
+	resp, err := w.client.CloseConnection(ctx, w.makeCloseConnectionRequest(closeRequests))
+	w.metrics.recordCloseRequestDuration(time.Since(start))
+	if err != nil {
+		w.metrics.recordCloseError(closeErrorKindControllerUnreachable)
+		results := make([]ConnectionCloseResult, 0, len(closeRequests))
+		for _, r := range closeRequests {
+			if w.logger != nil && r.Cause != nil {
+				w.logger.Error("error closing connection on controller",
+					"session_id", r.SessionId,
+					"connection_id", r.ConnectionId,
+					"reason", r.Reason.String(),
+					"cause", r.Cause,
+					"err", err,
+				)
+			}
+			results = append(results, ConnectionCloseResult{
+				ConnectionId: r.ConnectionId,
+				SessionId:    r.SessionId,
+				Result:       CloseResultControllerError,
+				Err:          fmt.Errorf("error closing connections on controller: %w", err),
+				Retryable:    isRetryableCause(r.Cause),
+			})
+		}
+		return results, err
+	}
+
+	results := w.setCloseTimeForResponse(closeRequests, resp)
+	w.dispatchCloseNotifications(ctx, closeRequests, results)
+	return results, nil
+}
+
+// makeCloseConnectionRequest builds the controller RPC request for the
+// given connections, tagging each with its caller-supplied close reason.
+func (w *Worker) makeCloseConnectionRequest(closeRequests []ConnectionCloseRequest) *pbs.CloseConnectionRequest {
+	closeRequestData := make([]*pbs.CloseConnectionRequestData, 0, len(closeRequests))
+	for _, r := range closeRequests {
+		closeRequestData = append(closeRequestData, &pbs.CloseConnectionRequestData{
+			ConnectionId: r.ConnectionId,
+			Reason:       r.reason().String(),
+		})
+	}
+	return &pbs.CloseConnectionRequest{
+		CloseRequestData: closeRequestData,
+	}
+}
+
+// makeSessionCloseInfo groups the controller's close response data by
+// session ID, using the session IDs supplied on closeRequests.
+func (w *Worker) makeSessionCloseInfo(closeRequests []ConnectionCloseRequest, response *pbs.CloseConnectionResponse) map[string][]*pbs.CloseConnectionResponseData {
+	if closeRequests == nil {
+		panic("closeRequests is nil")
+	}
+
+	connToSession := make(map[string]string, len(closeRequests))
+	for _, r := range closeRequests {
+		connToSession[r.ConnectionId] = r.SessionId
+	}
+
+	result := make(map[string][]*pbs.CloseConnectionResponseData)
+	for _, v := range response.GetCloseResponseData() {
+		sessionId := connToSession[v.GetConnectionId()]
+		result[sessionId] = append(result[sessionId], v)
+	}
+	return result
+}
+
+// setCloseTimeForResponse reconciles a controller close response against
+// local session/connection state, recording a close time for every
+// connection the controller confirmed as closed, and returns a
+// per-connection result describing what happened.
+func (w *Worker) setCloseTimeForResponse(closeRequests []ConnectionCloseRequest, response *pbs.CloseConnectionResponse) []ConnectionCloseResult {
+	sessionCloseInfo := w.makeSessionCloseInfo(closeRequests, response)
+
+	connToReason := make(map[string]string, len(closeRequests))
+	for _, r := range closeRequests {
+		connToReason[r.ConnectionId] = r.reason().String()
+	}
+
+	record := func(result ConnectionCloseResult) ConnectionCloseResult {
+		w.metrics.recordCloseResult(connToReason[result.ConnectionId], string(result.Result))
+		return result
+	}
+
+	results := make([]ConnectionCloseResult, 0, len(closeRequests))
+	for sessionId, responses := range sessionCloseInfo {
+		siRaw, ok := w.sessionInfoMap.Load(sessionId)
+		if !ok {
+			w.metrics.recordCloseError(closeErrorKindMissingSession)
+			for _, r := range responses {
+				results = append(results, record(ConnectionCloseResult{
+					ConnectionId: r.GetConnectionId(),
+					SessionId:    sessionId,
+					Result:       CloseResultSessionMissing,
+					Err:          fmt.Errorf("could not find session ID %q in local state after closing connections", sessionId),
+				}))
+			}
+			continue
+		}
+
+		si := siRaw.(*sessionInfo)
+		si.Lock()
+		for _, r := range responses {
+			if r.GetStatus() != pbs.CONNECTIONSTATUS_CONNECTIONSTATUS_CLOSED {
+				results = append(results, record(ConnectionCloseResult{
+					ConnectionId: r.GetConnectionId(),
+					SessionId:    sessionId,
+					Result:       CloseResultStillConnected,
+				}))
+				continue
+			}
+
+			ci, ok := si.connInfoMap[r.GetConnectionId()]
+			if !ok {
+				w.metrics.recordCloseError(closeErrorKindMissingConnection)
+				results = append(results, record(ConnectionCloseResult{
+					ConnectionId: r.GetConnectionId(),
+					SessionId:    sessionId,
+					Result:       CloseResultConnectionMissing,
+					Err:          fmt.Errorf("could not find connection ID %q for session ID %q in local state after closing connections", r.GetConnectionId(), sessionId),
+				}))
+				continue
+			}
+
+			ci.closeTime = time.Now()
+			results = append(results, record(ConnectionCloseResult{
+				ConnectionId: r.GetConnectionId(),
+				SessionId:    sessionId,
+				Result:       CloseResultClosed,
+			}))
+		}
+		si.Unlock()
+	}
+
+	w.refreshActiveConnections()
+	return results
+}
@@ -1,7 +1,9 @@
 package worker
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -11,13 +13,26 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func TestIsRetryableCause(t *testing.T) {
+	require := require.New(t)
+
+	require.True(isRetryableCause(nil))
+	require.True(isRetryableCause(errors.New("connection reset by peer")))
+	require.False(isRetryableCause(context.Canceled))
+	require.False(isRetryableCause(context.DeadlineExceeded))
+	require.False(isRetryableCause(fmt.Errorf("wrapped: %w", context.Canceled)))
+}
+
 func TestWorkerMakeCloseConnectionRequest(t *testing.T) {
 	require := require.New(t)
-	in := map[string]string{"foo": "one", "bar": "two"}
+	in := []ConnectionCloseRequest{
+		{ConnectionId: "foo", SessionId: "one", Reason: session.UnknownReason},
+		{ConnectionId: "bar", SessionId: "two", Reason: session.IdleTimeoutReason},
+	}
 	expected := &pbs.CloseConnectionRequest{
 		CloseRequestData: []*pbs.CloseConnectionRequestData{
 			{ConnectionId: "foo", Reason: session.UnknownReason.String()},
-			{ConnectionId: "bar", Reason: session.UnknownReason.String()},
+			{ConnectionId: "bar", Reason: session.IdleTimeoutReason.String()},
 		},
 	}
 	actual := new(Worker).makeCloseConnectionRequest(in)
@@ -26,7 +41,10 @@ func TestWorkerMakeCloseConnectionRequest(t *testing.T) {
 
 func TestMakeSessionCloseInfo(t *testing.T) {
 	require := require.New(t)
-	closeInfo := map[string]string{"foo": "one", "bar": "two"}
+	closeRequests := []ConnectionCloseRequest{
+		{ConnectionId: "foo", SessionId: "one"},
+		{ConnectionId: "bar", SessionId: "two"},
+	}
 	response := &pbs.CloseConnectionResponse{
 		CloseResponseData: []*pbs.CloseConnectionResponseData{
 			{ConnectionId: "foo", Status: pbs.CONNECTIONSTATUS_CONNECTIONSTATUS_CLOSED},
@@ -41,11 +59,11 @@ func TestMakeSessionCloseInfo(t *testing.T) {
 			{ConnectionId: "bar", Status: pbs.CONNECTIONSTATUS_CONNECTIONSTATUS_CLOSED},
 		},
 	}
-	actual := new(Worker).makeSessionCloseInfo(closeInfo, response)
+	actual := new(Worker).makeSessionCloseInfo(closeRequests, response)
 	require.Equal(expected, actual)
 }
 
-func TestMakeSessionCloseInfoPanicIfCloseInfoNil(t *testing.T) {
+func TestMakeSessionCloseInfoPanicIfCloseRequestsNil(t *testing.T) {
 	require := require.New(t)
 	require.Panics(func() {
 		new(Worker).makeSessionCloseInfo(nil, nil)
@@ -56,26 +74,28 @@ func TestMakeSessionCloseInfoEmpty(t *testing.T) {
 	require := require.New(t)
 	require.Equal(
 		make(map[string][]*pbs.CloseConnectionResponseData),
-		new(Worker).makeSessionCloseInfo(make(map[string]string), nil),
+		new(Worker).makeSessionCloseInfo([]ConnectionCloseRequest{}, nil),
 	)
 }
 
 func TestWorkerSetCloseTimeForResponse(t *testing.T) {
 	cases := []struct {
-		name             string
-		sessionCloseInfo map[string][]*pbs.CloseConnectionResponseData
-		sessionInfoMap   func() *sync.Map
-		expected         []string
-		expectedClosed   map[string]struct{}
-		expectedErr      []error
+		name           string
+		closeRequests  []ConnectionCloseRequest
+		response       *pbs.CloseConnectionResponse
+		sessionInfoMap func() *sync.Map
+		expectedClosed map[string]struct{}
+		expectedResult map[string]CloseResult
 	}{
 		{
 			name: "basic",
-			sessionCloseInfo: map[string][]*pbs.CloseConnectionResponseData{
-				"one": []*pbs.CloseConnectionResponseData{
+			closeRequests: []ConnectionCloseRequest{
+				{ConnectionId: "foo", SessionId: "one"},
+				{ConnectionId: "bar", SessionId: "two"},
+			},
+			response: &pbs.CloseConnectionResponse{
+				CloseResponseData: []*pbs.CloseConnectionResponseData{
 					{ConnectionId: "foo", Status: pbs.CONNECTIONSTATUS_CONNECTIONSTATUS_CLOSED},
-				},
-				"two": []*pbs.CloseConnectionResponseData{
 					{ConnectionId: "bar", Status: pbs.CONNECTIONSTATUS_CONNECTIONSTATUS_CLOSED},
 				},
 			},
@@ -102,19 +122,24 @@ func TestWorkerSetCloseTimeForResponse(t *testing.T) {
 
 				return m
 			},
-			expected: []string{"foo", "bar"},
 			expectedClosed: map[string]struct{}{
 				"foo": struct{}{},
 				"bar": struct{}{},
 			},
+			expectedResult: map[string]CloseResult{
+				"foo": CloseResultClosed,
+				"bar": CloseResultClosed,
+			},
 		},
 		{
 			name: "not closed",
-			sessionCloseInfo: map[string][]*pbs.CloseConnectionResponseData{
-				"one": []*pbs.CloseConnectionResponseData{
+			closeRequests: []ConnectionCloseRequest{
+				{ConnectionId: "foo", SessionId: "one"},
+				{ConnectionId: "bar", SessionId: "two"},
+			},
+			response: &pbs.CloseConnectionResponse{
+				CloseResponseData: []*pbs.CloseConnectionResponseData{
 					{ConnectionId: "foo", Status: pbs.CONNECTIONSTATUS_CONNECTIONSTATUS_CLOSED},
-				},
-				"two": []*pbs.CloseConnectionResponseData{
 					{ConnectionId: "bar", Status: pbs.CONNECTIONSTATUS_CONNECTIONSTATUS_CONNECTED},
 				},
 			},
@@ -135,18 +160,23 @@ func TestWorkerSetCloseTimeForResponse(t *testing.T) {
 
 				return m
 			},
-			expected: []string{"foo"},
 			expectedClosed: map[string]struct{}{
 				"foo": struct{}{},
 			},
+			expectedResult: map[string]CloseResult{
+				"foo": CloseResultClosed,
+				"bar": CloseResultStillConnected,
+			},
 		},
 		{
 			name: "missing session",
-			sessionCloseInfo: map[string][]*pbs.CloseConnectionResponseData{
-				"one": []*pbs.CloseConnectionResponseData{
+			closeRequests: []ConnectionCloseRequest{
+				{ConnectionId: "foo", SessionId: "one"},
+				{ConnectionId: "bar", SessionId: "two"},
+			},
+			response: &pbs.CloseConnectionResponse{
+				CloseResponseData: []*pbs.CloseConnectionResponseData{
 					{ConnectionId: "foo", Status: pbs.CONNECTIONSTATUS_CONNECTIONSTATUS_CLOSED},
-				},
-				"two": []*pbs.CloseConnectionResponseData{
 					{ConnectionId: "bar", Status: pbs.CONNECTIONSTATUS_CONNECTIONSTATUS_CLOSED},
 				},
 			},
@@ -161,21 +191,23 @@ func TestWorkerSetCloseTimeForResponse(t *testing.T) {
 
 				return m
 			},
-			expected: []string{"foo"},
 			expectedClosed: map[string]struct{}{
 				"foo": struct{}{},
 			},
-			expectedErr: []error{
-				errors.New(`could not find session ID "two" in local state after closing connections`),
+			expectedResult: map[string]CloseResult{
+				"foo": CloseResultClosed,
+				"bar": CloseResultSessionMissing,
 			},
 		},
 		{
 			name: "missing connection",
-			sessionCloseInfo: map[string][]*pbs.CloseConnectionResponseData{
-				"one": []*pbs.CloseConnectionResponseData{
+			closeRequests: []ConnectionCloseRequest{
+				{ConnectionId: "foo", SessionId: "one"},
+				{ConnectionId: "bar", SessionId: "two"},
+			},
+			response: &pbs.CloseConnectionResponse{
+				CloseResponseData: []*pbs.CloseConnectionResponseData{
 					{ConnectionId: "foo", Status: pbs.CONNECTIONSTATUS_CONNECTIONSTATUS_CLOSED},
-				},
-				"two": []*pbs.CloseConnectionResponseData{
 					{ConnectionId: "bar", Status: pbs.CONNECTIONSTATUS_CONNECTIONSTATUS_CLOSED},
 				},
 			},
@@ -191,17 +223,18 @@ func TestWorkerSetCloseTimeForResponse(t *testing.T) {
 
 				return m
 			},
-			expected: []string{"foo"},
 			expectedClosed: map[string]struct{}{
 				"foo": struct{}{},
 			},
-			expectedErr: []error{
-				errors.New(`could not find connection ID "bar" for session ID "two" in local state after closing connections`),
+			expectedResult: map[string]CloseResult{
+				"foo": CloseResultClosed,
+				"bar": CloseResultConnectionMissing,
 			},
 		},
 		{
-			name:             "empty",
-			sessionCloseInfo: make(map[string][]*pbs.CloseConnectionResponseData),
+			name:          "empty",
+			closeRequests: []ConnectionCloseRequest{},
+			response:      &pbs.CloseConnectionResponse{},
 			sessionInfoMap: func() *sync.Map {
 				m := new(sync.Map)
 				m.Store("one", &sessionInfo{
@@ -213,8 +246,8 @@ func TestWorkerSetCloseTimeForResponse(t *testing.T) {
 
 				return m
 			},
-			expected:       []string{},
 			expectedClosed: map[string]struct{}{},
+			expectedResult: map[string]CloseResult{},
 		},
 	}
 
@@ -225,7 +258,7 @@ func TestWorkerSetCloseTimeForResponse(t *testing.T) {
 			w := &Worker{
 				sessionInfoMap: tc.sessionInfoMap(),
 			}
-			actual, actualErr := w.setCloseTimeForResponse(tc.sessionCloseInfo)
+			actual := w.setCloseTimeForResponse(tc.closeRequests, tc.response)
 
 			// Assert all close times were set
 			w.sessionInfoMap.Range(func(key, value interface{}) bool {
@@ -241,9 +274,12 @@ func TestWorkerSetCloseTimeForResponse(t *testing.T) {
 				return true
 			})
 
-			// Assert return values
-			require.ElementsMatch(tc.expected, actual)
-			require.ElementsMatch(tc.expectedErr, actualErr)
+			// Assert per-connection results
+			actualResult := make(map[string]CloseResult, len(actual))
+			for _, r := range actual {
+				actualResult[r.ConnectionId] = r.Result
+			}
+			require.Equal(tc.expectedResult, actualResult)
 		})
 	}
 }
@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"sync"
+	"sync/atomic"
+
+	pbs "github.com/hashicorp/boundary/internal/gen/controller/servers/services"
+	"github.com/hashicorp/go-hclog"
+)
+
+// Worker is a worker that handles the actual proxying of connections to
+// target hosts and periodically reports session state back to the
+// controller.
+type Worker struct {
+	logger hclog.Logger
+
+	// client is used to talk to the controller's session service, e.g. to
+	// request that connections be closed.
+	client pbs.SessionServiceClient
+
+	// sessionInfoMap stores session ID -> *sessionInfo for every session
+	// this worker is currently proxying connections for.
+	sessionInfoMap *sync.Map
+
+	// deadlineExceededCh receives an event each time a connection's read,
+	// write, or absolute deadline fires; the deadline reaper goroutine
+	// drains it and closes the connection on the controller. It is
+	// created lazily by deadlineCh, guarded by deadlineChOnce, so it is
+	// never nil once a deadline has been armed.
+	deadlineExceededCh chan deadlineExceededEvent
+	deadlineChOnce     sync.Once
+
+	// closeNotifiers are notified of every connection close confirmed by
+	// the controller, e.g. to push the event to a billing or audit
+	// system.
+	closeNotifiers []CloseNotifier
+
+	// metrics holds the worker's close-path Prometheus collectors. It is
+	// nil until EnableMetrics is called, at which point all recording
+	// calls become live; recording against a nil metrics is always a
+	// no-op so instrumentation never requires a registry in tests.
+	metrics *metrics
+
+	// draining is set to 1 when Drain is called and is not cleared when
+	// Drain returns. Nothing in this package reads it other than
+	// isDraining. Access it via atomic operations, not directly.
+	draining int32
+
+	// drainWarner, if set, is used to push an in-band warning to a
+	// session's client before its connections are force-closed at the end
+	// of a drain grace period.
+	drainWarner DrainWarner
+}
+
+// isDraining reports whether the worker is currently draining.
+func (w *Worker) isDraining() bool {
+	return atomic.LoadInt32(&w.draining) == 1
+}